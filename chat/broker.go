@@ -8,20 +8,43 @@ type ChatMessage struct {
 	Sender    string
 	Content   string
 	Timestamp time.Time
+	Private   bool   // true if this is a private message (from /msg)
+	Recipient string // set when Private is true
 }
 
 // ChatClient represents a connected chat client
 type ChatClient struct {
-	Username string
-	Channel  chan ChatMessage
-	LastSeen int
+	Username    string
+	Channel     chan ChatMessage
+	LastSeen    int
+	Fingerprint string // SHA256 fingerprint of the client's public key
+	IsAdmin     bool
+	KickSignal  chan string // sent to when an admin kicks this client
+	JoinTime    time.Time
+	LastActive  time.Time
 }
 
 // Broker interface to interact with the message broker
 type Broker interface {
-	AddClient(username string) *ChatClient
+	AddClient(username, fingerprint string, isAdmin bool) *ChatClient
 	RemoveClient(username string)
 	SendMessage(sender, content string)
+	SendPrivate(from, to, content string) error
+	Rename(oldName, newName string) error
+	ListUsernames() []string
+	ListClients() []ChatClient
+	WhoIs(username string) (ChatClient, bool)
+
+	// Scroll returns up to limit older messages with ID < beforeID, oldest
+	// first, for backing the /scroll command and Page-Up scrollback.
+	Scroll(beforeID, limit int) []ChatMessage
+
+	// Moderation, available to admin clients only. The TUI is responsible
+	// for checking ChatClient.IsAdmin before invoking these.
+	Kick(username string) error
+	Mute(username string) error
+	Unmute(username string) error
+	Ban(username string) error
 }
 
 // Global broker instance will be set by the server
@@ -0,0 +1,193 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// actionPrefix marks a message as an emote (from /me) so refresh() can
+// render it as "* user does a thing" instead of "user: message".
+const actionPrefix = "\x01ACTION:"
+
+// dispatchCommand parses a "/"-prefixed input line and executes the
+// corresponding command, writing any response directly to the client.
+func (c *ChatTUI) dispatchCommand(input string) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return
+	}
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "/msg":
+		c.cmdMsg(args)
+	case "/me":
+		c.cmdMe(strings.TrimPrefix(input, cmd))
+	case "/nick":
+		c.cmdNick(args)
+	case "/list":
+		c.cmdList()
+	case "/whois":
+		c.cmdWhois(args)
+	case "/scroll":
+		c.cmdScroll(args)
+	case "/help":
+		c.cmdHelp()
+	case "/kick", "/ban", "/mute", "/unmute":
+		if !c.isAdmin {
+			c.writeLine(fmt.Sprintf("Unknown command: %s. Type /help for a list of commands.", cmd))
+			return
+		}
+		c.cmdModerate(cmd, args)
+	default:
+		c.writeLine(fmt.Sprintf("Unknown command: %s. Type /help for a list of commands.", cmd))
+	}
+}
+
+// cmdMsg sends a private message visible only to the sender and recipient.
+func (c *ChatTUI) cmdMsg(args []string) {
+	if len(args) < 2 {
+		c.writeLine("Usage: /msg <user> <message>")
+		return
+	}
+	target := args[0]
+	content := strings.Join(args[1:], " ")
+	if err := GlobalChatBroker.SendPrivate(c.username, target, content); err != nil {
+		c.writeLine(fmt.Sprintf("Could not message %s: %v", target, err))
+	}
+}
+
+// cmdMe broadcasts an emote, e.g. "/me waves" -> "* alice waves".
+func (c *ChatTUI) cmdMe(action string) {
+	action = strings.TrimSpace(action)
+	if action == "" {
+		c.writeLine("Usage: /me <action>")
+		return
+	}
+	GlobalChatBroker.SendMessage(c.username, actionPrefix+action)
+}
+
+// cmdNick changes the client's nickname, failing if it's already taken.
+func (c *ChatTUI) cmdNick(args []string) {
+	if len(args) != 1 {
+		c.writeLine("Usage: /nick <newname>")
+		return
+	}
+
+	oldName, newName := c.username, args[0]
+	if err := GlobalChatBroker.Rename(oldName, newName); err != nil {
+		c.writeLine(fmt.Sprintf("Could not change nickname: %v", err))
+		return
+	}
+
+	c.username = newName
+	GlobalChatBroker.SendMessage("System", fmt.Sprintf("%s is now known as %s", oldName, newName))
+}
+
+// cmdList shows every online user along with how long they've been idle.
+func (c *ChatTUI) cmdList() {
+	clients := GlobalChatBroker.ListClients()
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Username < clients[j].Username })
+
+	c.writeLine(fmt.Sprintf("%d user(s) online:", len(clients)))
+	for _, client := range clients {
+		role := ""
+		if client.IsAdmin {
+			role = " [admin]"
+		}
+		c.writeLine(fmt.Sprintf("  %s%s - idle %s", client.Username, role, time.Since(client.LastActive).Round(time.Second)))
+	}
+}
+
+// cmdWhois shows a user's public key fingerprint and session metadata.
+func (c *ChatTUI) cmdWhois(args []string) {
+	if len(args) != 1 {
+		c.writeLine("Usage: /whois <user>")
+		return
+	}
+
+	client, ok := GlobalChatBroker.WhoIs(args[0])
+	if !ok {
+		c.writeLine(fmt.Sprintf("No such user: %s", args[0]))
+		return
+	}
+
+	c.writeLine(fmt.Sprintf("%s: fingerprint %s, joined %s ago, idle %s",
+		client.Username, client.Fingerprint,
+		time.Since(client.JoinTime).Round(time.Second),
+		time.Since(client.LastActive).Round(time.Second)))
+}
+
+// cmdScroll loads older scrollback history (default 20 messages, or the
+// count given) and scrolls the view back to show it. Page-Up does the same.
+func (c *ChatTUI) cmdScroll(args []string) {
+	n := 20
+	if len(args) == 1 {
+		if parsed, err := strconv.Atoi(args[0]); err == nil && parsed > 0 {
+			n = parsed
+		}
+	}
+
+	loaded := c.loadOlderMessages(n)
+	if loaded == 0 {
+		c.writeLine("No more history.")
+		return
+	}
+
+	c.scrollOffset += loaded
+	c.fullRefresh()
+}
+
+// cmdHelp lists the available commands, including admin-only ones.
+func (c *ChatTUI) cmdHelp() {
+	c.writeLine("Available commands:")
+	c.writeLine("  /msg <user> <text>  - send a private message")
+	c.writeLine("  /me <action>        - perform an action")
+	c.writeLine("  /nick <name>        - change your nickname")
+	c.writeLine("  /list               - list online users")
+	c.writeLine("  /whois <user>       - show a user's fingerprint and session info")
+	c.writeLine("  /scroll [n]         - load n older messages (default 20); Page Up/Down also scroll")
+	c.writeLine("  /help               - show this message")
+
+	if c.isAdmin {
+		c.writeLine("  /kick <user>        - disconnect a user (admin)")
+		c.writeLine("  /ban <user>         - ban a user's public key (admin)")
+		c.writeLine("  /mute <user>        - silence a user (admin)")
+		c.writeLine("  /unmute <user>      - lift a mute (admin)")
+	}
+}
+
+// cmdModerate executes an admin-only moderation command. Callers must have
+// already checked c.isAdmin.
+func (c *ChatTUI) cmdModerate(cmd string, args []string) {
+	if len(args) != 1 {
+		c.writeLine(fmt.Sprintf("Usage: %s <user>", cmd))
+		return
+	}
+	// Allow "@user" as well as a bare username, since that's how other
+	// commands (e.g. /msg) are conventionally referenced.
+	target := strings.TrimPrefix(args[0], "@")
+
+	var err error
+	switch cmd {
+	case "/kick":
+		err = GlobalChatBroker.Kick(target)
+	case "/ban":
+		err = GlobalChatBroker.Ban(target)
+	case "/mute":
+		err = GlobalChatBroker.Mute(target)
+	case "/unmute":
+		err = GlobalChatBroker.Unmute(target)
+	}
+	if err != nil {
+		c.writeLine(fmt.Sprintf("Could not run %s %s: %v", cmd, target, err))
+	}
+}
+
+// writeLine writes a single line of command output to the client.
+func (c *ChatTUI) writeLine(msg string) {
+	c.channel.Write([]byte("\r\n" + msg + "\r\n"))
+}
@@ -5,8 +5,10 @@ import (
 	"io"
 	"log"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -15,37 +17,50 @@ import (
 type ChatTUI struct {
 	channel      ssh.Channel   // SSH channel for communication
 	username     string        // Username of the connected client
+	fingerprint  string        // SHA256 fingerprint of the client's public key
+	isAdmin      bool          // Whether this client has admin privileges
 	client       *ChatClient   // Associated chat client
 	currentInput string        // Current user input
+	inputCursor  int           // Rune index into currentInput where typing inserts
 	lastSent     time.Time     // Timestamp of last sent message
 	messages     []ChatMessage // Stored chat messages
 	running      bool          // Flag to indicate if TUI is running
 	refreshing   bool          // Flag to prevent concurrent refreshes
 	Resizing     bool          // Flag to indicate if a resize is in progress
-	width        int           // Terminal width
-	height       int           // Terminal height
+	width        int           // Terminal width, from pty-req/window-change
+	height       int           // Terminal height, from pty-req/window-change
 	resizeTimer  *time.Timer   // Timer for resize debounce
 	resizeMu     sync.Mutex    // Mutex for resize operations
+	scrollOffset int           // Number of messages scrolled back from the live tail
+	escSeq       []byte        // Partial ANSI escape sequence being accumulated
+	utf8Buf      []byte        // Bytes of a UTF-8 sequence still awaiting continuation bytes
+	history      []string      // Previously sent lines, oldest first
+	historyIdx   int           // Position while browsing history; len(history) means "not browsing"
+	historyTemp  string        // currentInput saved when history browsing began
 }
 
-// NewChatTUI creates a new chat TUI instance
-func NewChatTUI(channel ssh.Channel, username string) *ChatTUI {
+// NewChatTUI creates a new chat TUI instance. width and height are the
+// client's initial terminal size in character cells, typically parsed from
+// its pty-req; pass 0, 0 if unknown.
+func NewChatTUI(channel ssh.Channel, username, fingerprint string, isAdmin bool, width, height int) *ChatTUI {
 	return &ChatTUI{
-		channel:    channel,
-		username:   username,
-		messages:   make([]ChatMessage, 0),
-		lastSent:   time.Now(),
-		running:    false,
-		refreshing: false,
-		Resizing:   false,
-		width:      0,
-		height:     0,
+		channel:     channel,
+		username:    username,
+		fingerprint: fingerprint,
+		isAdmin:     isAdmin,
+		messages:    make([]ChatMessage, 0),
+		lastSent:    time.Now(),
+		running:     false,
+		refreshing:  false,
+		Resizing:    false,
+		width:       width,
+		height:      height,
 	}
 }
 
 // RunChatTUI starts the chat terminal user interface
-func RunChatTUI(channel ssh.Channel, username string) {
-	tui := NewChatTUI(channel, username)
+func RunChatTUI(channel ssh.Channel, username, fingerprint string, isAdmin bool, width, height int) {
+	tui := NewChatTUI(channel, username, fingerprint, isAdmin, width, height)
 	tui.Run()
 }
 
@@ -60,8 +75,11 @@ func (c *ChatTUI) Run() {
 		return
 	}
 
-	c.client = GlobalChatBroker.AddClient(c.username)
-	defer GlobalChatBroker.RemoveClient(c.username)
+	c.client = GlobalChatBroker.AddClient(c.username, c.fingerprint, c.isAdmin)
+	// c.username can change via /nick, so read it at call time rather than
+	// capturing today's value; otherwise a renamed client's broker entry,
+	// channels and forwarding goroutines are never cleaned up.
+	defer func() { GlobalChatBroker.RemoveClient(c.username) }()
 
 	// Send welcome message
 	GlobalChatBroker.SendMessage("System", fmt.Sprintf("%s joined the chat", c.username))
@@ -69,6 +87,9 @@ func (c *ChatTUI) Run() {
 	// Start goroutine to handle incoming messages from broker
 	go c.handleIncomingMessages()
 
+	// Start goroutine to watch for an admin kicking/banning this client
+	go c.watchKickSignal()
+
 	// Initial setup: screen refresh
 	c.refresh()
 
@@ -91,28 +112,46 @@ func (c *ChatTUI) Run() {
 
 		data := buffer[:n]
 		for _, b := range data {
+			if len(c.escSeq) > 0 || b == 27 {
+				c.escSeq = append(c.escSeq, b)
+				if c.feedEscSeq() {
+					c.escSeq = nil
+				}
+				continue
+			}
+
 			switch b {
 			case '\r', '\n': // Enter key
 				if time.Since(c.lastSent) < 5000*time.Millisecond {
 					continue
 				}
 				if c.currentInput != "" {
-					// Limit input to 200 characters
-					if len(c.currentInput) > 200 {
-						c.currentInput = c.currentInput[:200]
+					// Limit input to 200 runes. Truncating by byte index
+					// could split a multi-byte rune (insertRune allows the
+					// line to grow a few bytes past 200 to finish inserting
+					// one), producing invalid UTF-8 once broadcast.
+					if runes := []rune(c.currentInput); len(runes) > 200 {
+						c.currentInput = string(runes[:200])
+					}
+					// Lines starting with "/" are commands; everything else
+					// goes straight to the broker as a chat message.
+					if strings.HasPrefix(c.currentInput, "/") {
+						c.dispatchCommand(c.currentInput)
+					} else {
+						GlobalChatBroker.SendMessage(c.username, c.currentInput)
 					}
-					// Send message to broker
-					GlobalChatBroker.SendMessage(c.username, c.currentInput)
+					c.history = append(c.history, c.currentInput)
+					c.historyIdx = len(c.history)
+					c.historyTemp = ""
 					c.lastSent = time.Now()
 					c.currentInput = ""
+					c.inputCursor = 0
 					// Just move to new line and show prompt, let the message handler refresh
 					c.channel.Write([]byte("\r\n> "))
 				}
 			case 127, 8: // Backspace
-				if len(c.currentInput) > 0 {
-					c.currentInput = c.currentInput[:len(c.currentInput)-1]
-					c.fullRefresh()
-				}
+				c.deleteBeforeCursor()
+				c.fullRefresh()
 			case 3: // Ctrl+C
 				c.channel.Write([]byte("\r\nGoodbye!\r\n"))
 				GlobalChatBroker.SendMessage("System", fmt.Sprintf("%s left the chat", c.username))
@@ -123,21 +162,197 @@ func (c *ChatTUI) Run() {
 				return
 			case 12: // Ctrl+L (refresh)
 				c.refresh()
-				if c.currentInput != "" {
-					c.channel.Write([]byte(c.currentInput))
-				}
 			default:
-				if b >= 32 && b <= 126 { // Printable characters
-					if len(c.currentInput) < 200 {
-						c.currentInput += string(b)
-						c.channel.Write([]byte(string(b)))
+				if b < 32 { // Unhandled control byte
+					continue
+				}
+				// Accumulate bytes until they form a complete UTF-8 rune, so
+				// multi-byte characters split across Read() calls still decode.
+				c.utf8Buf = append(c.utf8Buf, b)
+				for utf8.FullRune(c.utf8Buf) {
+					r, size := utf8.DecodeRune(c.utf8Buf)
+					c.utf8Buf = c.utf8Buf[size:]
+					if r == utf8.RuneError && size == 1 {
+						continue // drop the invalid byte
 					}
+					c.insertRune(r)
+					c.fullRefresh()
 				}
 			}
 		}
 	}
 }
 
+// feedEscSeq advances the partial ANSI escape sequence in c.escSeq by one
+// byte (already appended by the caller) and, once the sequence is complete,
+// dispatches it and reports true so the caller can reset c.escSeq.
+// Recognized sequences: Up/Down (history recall), Left/Right (cursor
+// movement), and Page-Up/Page-Down (scrollback, ESC [ 5 ~ / ESC [ 6 ~).
+func (c *ChatTUI) feedEscSeq() bool {
+	switch len(c.escSeq) {
+	case 1:
+		return false // waiting for '['
+	case 2:
+		return c.escSeq[1] != '['
+	case 3:
+		switch c.escSeq[2] {
+		case '5', '6':
+			return false // waiting for the trailing '~'
+		case 'A':
+			c.historyUp()
+			c.fullRefresh()
+		case 'B':
+			c.historyDown()
+			c.fullRefresh()
+		case 'C':
+			c.moveCursor(1)
+			c.fullRefresh()
+		case 'D':
+			c.moveCursor(-1)
+			c.fullRefresh()
+		default:
+			// Unrecognized 3-byte sequence; ignored.
+		}
+		return true
+	case 4:
+		if c.escSeq[3] == '~' {
+			switch c.escSeq[2] {
+			case '5':
+				c.scrollBack(20)
+			case '6':
+				c.scrollForward(20)
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// insertRune inserts r at the cursor position and advances the cursor.
+func (c *ChatTUI) insertRune(r rune) {
+	if len(c.currentInput) >= 200 {
+		return
+	}
+	runes := []rune(c.currentInput)
+	runes = append(runes, 0)
+	copy(runes[c.inputCursor+1:], runes[c.inputCursor:])
+	runes[c.inputCursor] = r
+	c.currentInput = string(runes)
+	c.inputCursor++
+}
+
+// deleteBeforeCursor removes the rune immediately before the cursor, i.e.
+// handles Backspace. It's a no-op at the start of the line.
+func (c *ChatTUI) deleteBeforeCursor() {
+	if c.inputCursor == 0 {
+		return
+	}
+	runes := []rune(c.currentInput)
+	runes = append(runes[:c.inputCursor-1], runes[c.inputCursor:]...)
+	c.currentInput = string(runes)
+	c.inputCursor--
+}
+
+// moveCursor shifts the cursor by delta runes, clamped to the input's bounds.
+func (c *ChatTUI) moveCursor(delta int) {
+	c.inputCursor += delta
+	if c.inputCursor < 0 {
+		c.inputCursor = 0
+	}
+	if max := len([]rune(c.currentInput)); c.inputCursor > max {
+		c.inputCursor = max
+	}
+}
+
+// historyUp recalls the previous sent line, saving the in-progress input the
+// first time it's called so Down can restore it later.
+func (c *ChatTUI) historyUp() {
+	if len(c.history) == 0 || c.historyIdx == 0 {
+		return
+	}
+	if c.historyIdx == len(c.history) {
+		c.historyTemp = c.currentInput
+	}
+	c.historyIdx--
+	c.currentInput = c.history[c.historyIdx]
+	c.inputCursor = len([]rune(c.currentInput))
+}
+
+// historyDown moves forward through sent-line history, restoring the
+// in-progress input once the newest entry is passed.
+func (c *ChatTUI) historyDown() {
+	if c.historyIdx >= len(c.history) {
+		return
+	}
+	c.historyIdx++
+	if c.historyIdx == len(c.history) {
+		c.currentInput = c.historyTemp
+	} else {
+		c.currentInput = c.history[c.historyIdx]
+	}
+	c.inputCursor = len([]rune(c.currentInput))
+}
+
+// scrollBack moves the view n messages further into scrollback history,
+// loading older messages from the broker if the local buffer runs out.
+func (c *ChatTUI) scrollBack(n int) {
+	if c.scrollOffset+n > len(c.messages) {
+		c.loadOlderMessages(n)
+	}
+	c.scrollOffset += n
+	if c.scrollOffset > len(c.messages) {
+		c.scrollOffset = len(c.messages)
+	}
+	c.fullRefresh()
+}
+
+// scrollForward moves the view n messages back toward the live tail.
+func (c *ChatTUI) scrollForward(n int) {
+	c.scrollOffset -= n
+	if c.scrollOffset < 0 {
+		c.scrollOffset = 0
+	}
+	c.fullRefresh()
+}
+
+// loadOlderMessages fetches up to n messages older than the oldest one
+// currently held in memory and prepends them. It returns the number of
+// messages actually loaded.
+func (c *ChatTUI) loadOlderMessages(n int) int {
+	if GlobalChatBroker == nil {
+		return 0
+	}
+
+	beforeID := 0
+	if len(c.messages) > 0 {
+		beforeID = c.messages[0].ID
+	}
+
+	older := GlobalChatBroker.Scroll(beforeID, n)
+	if len(older) == 0 {
+		return 0
+	}
+
+	c.messages = append(older, c.messages...)
+	return len(older)
+}
+
+// watchKickSignal disconnects the session when an admin kicks or bans this
+// client. The SSH channel read in Run() is blocking, so closing the channel
+// is what actually unblocks and terminates the main loop.
+func (c *ChatTUI) watchKickSignal() {
+	if c.client == nil || c.client.KickSignal == nil {
+		return
+	}
+	reason, ok := <-c.client.KickSignal
+	if !ok {
+		return
+	}
+	c.channel.Write([]byte(fmt.Sprintf("\r\nDisconnected: %s\r\n", reason)))
+	c.channel.Close()
+}
+
 // handleIncomingMessages processes messages from the broker
 func (c *ChatTUI) handleIncomingMessages() {
 	for message := range c.client.Channel {
@@ -148,13 +363,49 @@ func (c *ChatTUI) handleIncomingMessages() {
 	}
 }
 
-// fullRefresh redraws the screen in a resize-safe way
+// fullRefresh redraws the screen. It's kept as a separate entry point from
+// refresh() so call sites read as "redraw everything including input" even
+// though refresh() already covers the input line itself.
 func (c *ChatTUI) fullRefresh() {
 	c.refresh()
-	c.channel.Write([]byte(c.currentInput))
 }
 
-// refresh performs a complete screen refresh in a resize-safe way
+// defaultMessagePaneHeight is how many message lines to show when the
+// client's terminal size is unknown (no pty-req was ever received).
+const defaultMessagePaneHeight = 20
+
+// minMessagePaneHeight is the smallest message pane refresh() will lay out,
+// even on a very short terminal.
+const minMessagePaneHeight = 5
+
+// reservedLines is how many screen rows refresh()'s header, user-list pane,
+// separators and input line take up, leaving the rest for messages.
+const reservedLines = 7
+
+// messagePaneHeight returns how many message lines fit in the current
+// terminal, based on the size reported via pty-req/window-change.
+func (c *ChatTUI) messagePaneHeight() int {
+	if c.height <= reservedLines {
+		return defaultMessagePaneHeight
+	}
+	if h := c.height - reservedLines; h > minMessagePaneHeight {
+		return h
+	}
+	return minMessagePaneHeight
+}
+
+// paneWidth returns the terminal width to use for divider lines, falling
+// back to a conventional 80 columns when unknown.
+func (c *ChatTUI) paneWidth() int {
+	if c.width <= 0 {
+		return 80
+	}
+	return c.width
+}
+
+// refresh performs a complete screen refresh in a resize-safe way. The
+// screen is laid out as three panes, separated by horizontal rules: a user
+// list, the message log (respecting scrollOffset), and the input line.
 func (c *ChatTUI) refresh() {
 	// Prevent concurrent refreshes
 	if c.refreshing {
@@ -171,9 +422,15 @@ func (c *ChatTUI) refresh() {
 	c.channel.Write([]byte("\033[2J\033[H")) // Clear screen and go to top
 	c.channel.Write([]byte("\033[?25h"))     // Ensure cursor is visible
 
+	divider := strings.Repeat("-", c.paneWidth())
+
 	// Draw header
 	c.channel.Write([]byte(fmt.Sprintf("===== %s@cer.sh chat =====\r\n", c.username)))
-	c.channel.Write([]byte("Online users: "))
+	c.channel.Write([]byte("Type your message and press Enter, (5 second cooldown). Ctrl+C to quit. Ctrl+L to refresh. /help for commands.\r\n"))
+	c.channel.Write([]byte(divider + "\r\n"))
+
+	// User-list pane
+	c.channel.Write([]byte("Online: "))
 	usernames := GlobalChatBroker.ListUsernames()
 	sort.Strings(usernames)
 	for i, user := range usernames {
@@ -183,29 +440,47 @@ func (c *ChatTUI) refresh() {
 		c.channel.Write([]byte(user))
 	}
 	c.channel.Write([]byte("\r\n"))
-	c.channel.Write([]byte("Type your message and press Enter, (5 second cooldown). Ctrl+C to quit. Ctrl+L to refresh.\r\n\r\n"))
+	c.channel.Write([]byte(divider + "\r\n"))
 
-	// Display messages (limit to last 50 to prevent screen overflow)
-	messageCount := len(c.messages)
+	// Message-log pane: a window sized to the terminal height, shifted back
+	// by scrollOffset when the user has scrolled up with /scroll or Page-Up.
+	paneHeight := c.messagePaneHeight()
+	messageCount := len(c.messages) - c.scrollOffset
+	if messageCount < 0 {
+		messageCount = 0
+	}
 	startIdx := 0
-	if messageCount > 20 {
-		startIdx = messageCount - 20
+	if messageCount > paneHeight {
+		startIdx = messageCount - paneHeight
+	}
+	if c.scrollOffset > 0 {
+		c.channel.Write([]byte(fmt.Sprintf("-- scrolled back %d message(s), Page Down to return toward live --\r\n", c.scrollOffset)))
 	}
 
 	for i := startIdx; i < messageCount; i++ {
 		msg := c.messages[i]
 		timestamp := msg.Timestamp.Format("15:04:05")
 		var formattedMsg string
-		if msg.Sender == "System" {
+		switch {
+		case msg.Sender == "System":
 			formattedMsg = fmt.Sprintf("[%s] ** %s **\r\n", timestamp, msg.Content)
-		} else {
+		case msg.Private:
+			formattedMsg = fmt.Sprintf("[%s] (private) %s -> %s: %s\r\n", timestamp, msg.Sender, msg.Recipient, msg.Content)
+		case strings.HasPrefix(msg.Content, actionPrefix):
+			formattedMsg = fmt.Sprintf("[%s] * %s %s\r\n", timestamp, msg.Sender, strings.TrimPrefix(msg.Content, actionPrefix))
+		default:
 			formattedMsg = fmt.Sprintf("[%s] %s: %s\r\n", timestamp, msg.Sender, msg.Content)
 		}
 		c.channel.Write([]byte(formattedMsg))
 	}
 
-	// Show prompt at the end
-	c.channel.Write([]byte("> "))
+	// Input pane: the prompt plus the in-progress line, with the cursor
+	// repositioned if it isn't at the end (e.g. after Left/Right editing).
+	c.channel.Write([]byte(divider + "\r\n"))
+	c.channel.Write([]byte("> " + c.currentInput))
+	if back := len([]rune(c.currentInput)) - c.inputCursor; back > 0 {
+		c.channel.Write([]byte(fmt.Sprintf("\033[%dD", back)))
+	}
 }
 
 // HandleResize handles terminal resize events
@@ -1,29 +1,72 @@
 package keys
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"golang.org/x/crypto/ssh"
 )
 
-// loadOrGenerateHostKey loads an existing SSH host key or generates a new one
-func LoadOrGenerateHostKey(filename string) (ssh.Signer, error) {
+// KeyType identifies the algorithm used for a generated host key.
+type KeyType string
+
+const (
+	KeyTypeEd25519   KeyType = "ed25519"
+	KeyTypeECDSAP256 KeyType = "ecdsa-p256"
+	KeyTypeRSA       KeyType = "rsa"
+)
+
+// LoadOrGenerateHostKey loads an existing SSH host key from filename, or
+// generates a new key of the given type and persists it there in OpenSSH
+// private-key format if no file exists yet.
+func LoadOrGenerateHostKey(filename string, keyType KeyType) (ssh.Signer, error) {
 	// Try to load existing key
 	if _, err := os.Stat(filename); err == nil {
 		return loadExistingKey(filename)
 	}
 
 	// Generate new key if file doesn't exist
-	return generateNewKey(filename)
+	return generateNewKey(filename, keyType)
+}
+
+// LoadHostKeyDir loads every host key file in dir, so the caller can offer
+// clients whichever key type they prefer (e.g. ed25519) instead of being
+// downgraded to or rejected by a single key type.
+func LoadHostKeyDir(dir string) ([]ssh.Signer, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host key directory: %v", err)
+	}
+
+	var signers []ssh.Signer
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		signer, err := loadExistingKey(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("Skipping %s: %v", entry.Name(), err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no usable host keys found in %s", dir)
+	}
+	return signers, nil
 }
 
-// loadExistingKey loads an SSH host key from a file
+// loadExistingKey loads an SSH host key from a file, auto-detecting
+// PKCS#1, PKCS#8, and OpenSSH private-key PEM formats.
 func loadExistingKey(filename string) (ssh.Signer, error) {
 	log.Printf("Loading existing host key from %s", filename)
 	keyBytes, err := os.ReadFile(filename)
@@ -31,17 +74,12 @@ func loadExistingKey(filename string) (ssh.Signer, error) {
 		return nil, fmt.Errorf("failed to read host key file: %v", err)
 	}
 
-	block, _ := pem.Decode(keyBytes)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block from host key file")
-	}
-
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	rawKey, err := ssh.ParseRawPrivateKey(keyBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse private key: %v", err)
 	}
 
-	signer, err := ssh.NewSignerFromKey(privateKey)
+	signer, err := ssh.NewSignerFromKey(rawKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create signer from key: %v", err)
 	}
@@ -49,20 +87,20 @@ func loadExistingKey(filename string) (ssh.Signer, error) {
 	return signer, nil
 }
 
-// generateNewKey creates a new SSH host key and saves it to a file
-func generateNewKey(filename string) (ssh.Signer, error) {
-	log.Printf("Generating new host key and saving to %s", filename)
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+// generateNewKey creates a new SSH host key of the given type and saves it
+// to a file in OpenSSH private-key format.
+func generateNewKey(filename string, keyType KeyType) (ssh.Signer, error) {
+	log.Printf("Generating new %s host key and saving to %s", keyType, filename)
+
+	privateKey, err := generatePrivateKey(keyType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate RSA key: %v", err)
+		return nil, err
 	}
 
-	// Save the key to file
 	if err := saveKeyToFile(privateKey, filename); err != nil {
 		return nil, err
 	}
 
-	// Convert to SSH format
 	signer, err := ssh.NewSignerFromKey(privateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create signer from key: %v", err)
@@ -71,31 +109,50 @@ func generateNewKey(filename string) (ssh.Signer, error) {
 	return signer, nil
 }
 
-// saveKeyToFile saves an RSA private key to a PEM file
-func saveKeyToFile(privateKey *rsa.PrivateKey, filename string) error {
-	keyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
-	pemBlock := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: keyBytes,
+// generatePrivateKey creates a new private key for the given key type.
+func generatePrivateKey(keyType KeyType) (interface{}, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ed25519 key: %v", err)
+		}
+		return privateKey, nil
+	case KeyTypeECDSAP256:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ecdsa key: %v", err)
+		}
+		return privateKey, nil
+	case KeyTypeRSA, "":
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %v", err)
+		}
+		return privateKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// saveKeyToFile saves a private key to a PEM file in OpenSSH format.
+func saveKeyToFile(privateKey interface{}, filename string) error {
+	pemBlock, err := ssh.MarshalPrivateKey(privateKey, "ssh-chat-server host key")
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %v", err)
 	}
 
 	// Check if the directory exists, create it if not
-	if err := os.MkdirAll(".keystore", 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
 		return fmt.Errorf("failed to create key directory: %v", err)
 	}
 
-	keyFile, err := os.Create(filename)
-
+	keyFile, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to create host key file: %v", err)
 	}
 	defer keyFile.Close()
 
-	// Set restrictive permissions (readable only by owner)
-	if err := keyFile.Chmod(0600); err != nil {
-		return fmt.Errorf("failed to set key file permissions: %v", err)
-	}
-
 	if err := pem.Encode(keyFile, pemBlock); err != nil {
 		return fmt.Errorf("failed to write host key to file: %v", err)
 	}
@@ -1,21 +1,48 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"log"
+	"os"
+	"strings"
 
 	"ssh-chat-server/keys"
 	"ssh-chat-server/server"
+
+	"golang.org/x/crypto/ssh"
 )
 
 func main() {
-	// Generate or load SSH host key
-	hostKey, err := keys.LoadOrGenerateHostKey(".keystore/sshHostKey.private")
+	adminFlag := flag.String("admin", "", "comma-separated list of admin public key fingerprints (SHA256:...)")
+	whitelistFlag := flag.String("whitelist", "", "path to a file of whitelisted public key fingerprints, one per line")
+	keyTypeFlag := flag.String("key-type", string(keys.KeyTypeEd25519), "host key type to generate if none exists: ed25519, ecdsa-p256, or rsa")
+	hostKeyDirFlag := flag.String("host-key-dir", "", "directory of host key files to offer clients, instead of a single generated key")
+	flag.Parse()
+
+	var admins []string
+	if *adminFlag != "" {
+		for _, fp := range strings.Split(*adminFlag, ",") {
+			admins = append(admins, strings.TrimSpace(fp))
+		}
+	}
+
+	var whitelist []string
+	if *whitelistFlag != "" {
+		var err error
+		whitelist, err = loadFingerprintList(*whitelistFlag)
+		if err != nil {
+			log.Fatal("Failed to load whitelist:", err)
+		}
+	}
+
+	hostKeys, err := loadHostKeys(*hostKeyDirFlag, keys.KeyType(*keyTypeFlag))
 	if err != nil {
-		log.Fatal("Failed to load or generate host key:", err)
+		log.Fatal("Failed to load host keys:", err)
 	}
 
 	// Create and start SSH server
-	sshServer, err := server.NewSSHServer("1234", hostKey)
+	sshServer, err := server.NewSSHServer("1234", hostKeys, admins, whitelist)
 	if err != nil {
 		log.Fatal("Failed to create SSH server:", err)
 	}
@@ -25,3 +52,39 @@ func main() {
 		log.Fatal("SSH server error:", err)
 	}
 }
+
+// loadHostKeys loads the server's host keys. If hostKeyDir is set, every key
+// file in that directory is offered to clients; otherwise a single key of
+// keyType is loaded or generated at the default keystore path.
+func loadHostKeys(hostKeyDir string, keyType keys.KeyType) ([]ssh.Signer, error) {
+	if hostKeyDir != "" {
+		return keys.LoadHostKeyDir(hostKeyDir)
+	}
+
+	hostKey, err := keys.LoadOrGenerateHostKey(".keystore/sshHostKey.private", keyType)
+	if err != nil {
+		return nil, err
+	}
+	return []ssh.Signer{hostKey}, nil
+}
+
+// loadFingerprintList reads a whitelist file containing one public key
+// fingerprint per line, ignoring blank lines and "#" comments.
+func loadFingerprintList(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var fingerprints []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fingerprints = append(fingerprints, line)
+	}
+	return fingerprints, scanner.Err()
+}
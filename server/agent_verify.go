@@ -0,0 +1,39 @@
+package server
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// verifyViaAgent re-confirms that the client genuinely controls the key
+// behind the fingerprint recorded at handshake time, on top of the
+// signature the standard publickey auth method already verified. It opens
+// the "auth-agent@openssh.com" channel the client made available via an
+// earlier auth-agent-req@openssh.com request, asks the forwarded agent to
+// sign a fresh server-generated nonce with key, and checks the signature
+// itself. This guards against a client whose agent holds a different key
+// than the one it authenticated with.
+func verifyViaAgent(conn ssh.Conn, key ssh.PublicKey) error {
+	agentChan, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return fmt.Errorf("no forwarded ssh-agent is available: %v", err)
+	}
+	defer agentChan.Close()
+	go ssh.DiscardRequests(reqs)
+
+	ag := agent.NewClient(agentChan)
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate challenge nonce: %v", err)
+	}
+
+	sig, err := ag.Sign(key, nonce)
+	if err != nil {
+		return fmt.Errorf("agent refused to sign challenge: %v", err)
+	}
+	return key.Verify(nonce, sig)
+}
@@ -13,14 +13,19 @@ func NewBrokerAdapter(broker *MessageBroker) *BrokerAdapter {
 }
 
 // AddClient implements chat.Broker interface
-func (ba *BrokerAdapter) AddClient(username string) *chat.ChatClient {
-	client := ba.broker.AddClient(username)
+func (ba *BrokerAdapter) AddClient(username, fingerprint string, isAdmin bool) *chat.ChatClient {
+	client := ba.broker.AddClient(username, fingerprint, isAdmin)
 
 	// Convert to chat.ChatClient
 	chatClient := &chat.ChatClient{
-		Username: client.Username,
-		Channel:  make(chan chat.ChatMessage, 100),
-		LastSeen: client.LastSeen,
+		Username:    client.Username,
+		Channel:     make(chan chat.ChatMessage, 100),
+		LastSeen:    client.LastSeen,
+		Fingerprint: client.Fingerprint,
+		IsAdmin:     client.IsAdmin,
+		KickSignal:  client.KickCh,
+		JoinTime:    client.JoinTime,
+		LastActive:  client.LastActive,
 	}
 
 	// Start goroutine to convert messages
@@ -31,6 +36,8 @@ func (ba *BrokerAdapter) AddClient(username string) *chat.ChatClient {
 				Sender:    msg.Sender,
 				Content:   msg.Content,
 				Timestamp: msg.Timestamp,
+				Private:   msg.Private,
+				Recipient: msg.Recipient,
 			}
 			select {
 			case chatClient.Channel <- chatMsg:
@@ -52,3 +59,86 @@ func (ba *BrokerAdapter) RemoveClient(username string) {
 func (ba *BrokerAdapter) SendMessage(sender, content string) {
 	ba.broker.SendMessage(sender, content)
 }
+
+// ListUsernames implements chat.Broker interface
+func (ba *BrokerAdapter) ListUsernames() []string {
+	return ba.broker.ListUsernames()
+}
+
+// Kick implements chat.Broker interface
+func (ba *BrokerAdapter) Kick(username string) error {
+	return ba.broker.Kick(username)
+}
+
+// Mute implements chat.Broker interface
+func (ba *BrokerAdapter) Mute(username string) error {
+	return ba.broker.Mute(username)
+}
+
+// Unmute implements chat.Broker interface
+func (ba *BrokerAdapter) Unmute(username string) error {
+	return ba.broker.Unmute(username)
+}
+
+// Ban implements chat.Broker interface
+func (ba *BrokerAdapter) Ban(username string) error {
+	return ba.broker.Ban(username)
+}
+
+// SendPrivate implements chat.Broker interface
+func (ba *BrokerAdapter) SendPrivate(from, to, content string) error {
+	return ba.broker.SendPrivate(from, to, content)
+}
+
+// Rename implements chat.Broker interface
+func (ba *BrokerAdapter) Rename(oldName, newName string) error {
+	return ba.broker.Rename(oldName, newName)
+}
+
+// ListClients implements chat.Broker interface
+func (ba *BrokerAdapter) ListClients() []chat.ChatClient {
+	clients := ba.broker.ListClients()
+	result := make([]chat.ChatClient, len(clients))
+	for i, c := range clients {
+		result[i] = chat.ChatClient{
+			Username:    c.Username,
+			Fingerprint: c.Fingerprint,
+			IsAdmin:     c.IsAdmin,
+			JoinTime:    c.JoinTime,
+			LastActive:  c.LastActive,
+		}
+	}
+	return result
+}
+
+// WhoIs implements chat.Broker interface
+func (ba *BrokerAdapter) WhoIs(username string) (chat.ChatClient, bool) {
+	c, ok := ba.broker.WhoIs(username)
+	if !ok {
+		return chat.ChatClient{}, false
+	}
+	return chat.ChatClient{
+		Username:    c.Username,
+		Fingerprint: c.Fingerprint,
+		IsAdmin:     c.IsAdmin,
+		JoinTime:    c.JoinTime,
+		LastActive:  c.LastActive,
+	}, true
+}
+
+// Scroll implements chat.Broker interface
+func (ba *BrokerAdapter) Scroll(beforeID, limit int) []chat.ChatMessage {
+	messages := ba.broker.MessagesBefore(beforeID, limit)
+	result := make([]chat.ChatMessage, len(messages))
+	for i, msg := range messages {
+		result[i] = chat.ChatMessage{
+			ID:        msg.ID,
+			Sender:    msg.Sender,
+			Content:   msg.Content,
+			Timestamp: msg.Timestamp,
+			Private:   msg.Private,
+			Recipient: msg.Recipient,
+		}
+	}
+	return result
+}
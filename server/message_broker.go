@@ -1,83 +1,352 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// defaultRingCapacity bounds how many recent messages are kept in memory
+// for scrollback; older messages are only reachable via the persisted log.
+const defaultRingCapacity = 1024
+
+// defaultScrollbackLog is where the append-only scrollback log is written.
+const defaultScrollbackLog = ".keystore/scrollback.jsonl"
+
+// defaultBansFile is where banned public key fingerprints are persisted.
+const defaultBansFile = ".keystore/bans.json"
+
 // Message represents a chat message
 type Message struct {
 	ID        int       `json:"id"`
 	Sender    string    `json:"sender"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Private   bool      `json:"private,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
 }
 
 // Client represents a connected chat client
 type Client struct {
-	Username string
-	Channel  chan Message
-	LastSeen int // Last message ID seen by this client
+	Username    string
+	Channel     chan Message
+	LastSeen    int // ID of the newest message backfilled to this client
+	Fingerprint string
+	IsAdmin     bool
+	Muted       bool
+	KickCh      chan string // signaled with a reason when an admin kicks this client
+	JoinTime    time.Time
+	LastActive  time.Time
+	closed      bool // true once RemoveClient has closed Channel; guarded by MessageBroker.mu
 }
 
 // MessageBroker manages all chat messages and clients
 type MessageBroker struct {
 	mu          sync.RWMutex
-	messages    []Message
-	clients     map[string]*Client
+	ring        []Message // circular buffer of up to ringCap recent messages
+	ringCap     int
 	nextID      int
+	clients     map[string]*Client
 	nextMessage chan Message
+	banned      map[string]bool // banned public key fingerprints
+	bansPath    string          // where banned is persisted as JSON; "" disables it
+	logFile     *os.File        // append-only scrollback persistence; nil disables it
 }
 
-// NewMessageBroker creates a new message broker
+// NewMessageBroker creates a message broker with the default ring capacity,
+// persisting scrollback and bans to their default on-disk locations.
 func NewMessageBroker() *MessageBroker {
+	broker, err := NewMessageBrokerWithCapacity(defaultRingCapacity, defaultScrollbackLog, defaultBansFile)
+	if err != nil {
+		log.Printf("Scrollback persistence disabled: %v", err)
+		broker, _ = NewMessageBrokerWithCapacity(defaultRingCapacity, "", defaultBansFile)
+	}
+	return broker
+}
+
+// NewMessageBrokerWithCapacity creates a message broker whose scrollback
+// ring holds up to ringCap messages. If logPath is non-empty, existing
+// scrollback is replayed from it and every future message is appended to
+// it, so history survives a restart even though the in-memory ring doesn't.
+// If bansPath is non-empty, previously banned fingerprints are loaded from
+// it and every subsequent ban is persisted there too.
+func NewMessageBrokerWithCapacity(ringCap int, logPath, bansPath string) (*MessageBroker, error) {
 	broker := &MessageBroker{
-		messages:    make([]Message, 0),
-		clients:     make(map[string]*Client),
+		ring:        make([]Message, 0, ringCap),
+		ringCap:     ringCap,
 		nextID:      1,
+		clients:     make(map[string]*Client),
 		nextMessage: make(chan Message, 100),
+		banned:      make(map[string]bool),
+	}
+
+	if logPath != "" {
+		if err := broker.openScrollbackLog(logPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if bansPath != "" {
+		if err := broker.loadBans(bansPath); err != nil {
+			return nil, err
+		}
+		broker.bansPath = bansPath
 	}
 
 	// Start the message distribution goroutine
 	go broker.distributeMessages()
 
-	return broker
+	return broker, nil
 }
 
-// Global message broker instance
-var GlobalBroker = NewMessageBroker()
+// openScrollbackLog replays any existing log at path into the ring buffer,
+// then opens it for appending future messages.
+func (mb *MessageBroker) openScrollbackLog(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create scrollback directory: %v", err)
+		}
+	}
 
-// AddClient registers a new client with the broker
-func (mb *MessageBroker) AddClient(username string) *Client {
-	mb.mu.Lock()
-	defer mb.mu.Unlock()
+	if existing, err := os.ReadFile(path); err == nil {
+		mb.loadScrollback(existing)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read scrollback log: %v", err)
+	}
 
-	client := &Client{
-		Username: username,
-		Channel:  make(chan Message, 100),
-		LastSeen: 0,
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open scrollback log: %v", err)
 	}
+	mb.logFile = file
+	return nil
+}
 
-	mb.clients[username] = client
+// loadScrollback seeds the ring buffer from a previously persisted log.
+func (mb *MessageBroker) loadScrollback(data []byte) {
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			log.Printf("Skipping malformed scrollback entry: %v", err)
+			continue
+		}
+		mb.storeInRing(msg)
+		if msg.ID >= mb.nextID {
+			mb.nextID = msg.ID + 1
+		}
+	}
+}
 
-	// Send all existing messages to the new client
-	for _, msg := range mb.messages {
-		select {
-		case client.Channel <- msg:
-		default:
-			// Channel full, skip
+// storeInRing writes msg into its ring slot. Callers must hold mb.mu (or
+// call this during single-threaded startup).
+func (mb *MessageBroker) storeInRing(msg Message) {
+	idx := (msg.ID - 1) % mb.ringCap
+	if idx < len(mb.ring) {
+		mb.ring[idx] = msg
+	} else {
+		mb.ring = append(mb.ring, msg)
+	}
+}
+
+// persistLocked appends msg to the scrollback log, if enabled. Callers must
+// hold mb.mu.
+func (mb *MessageBroker) persistLocked(msg Message) {
+	if mb.logFile == nil {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal message for scrollback: %v", err)
+		return
+	}
+	data = append(data, '\n')
+	if _, err := mb.logFile.Write(data); err != nil {
+		log.Printf("Failed to persist message to scrollback log: %v", err)
+	}
+}
+
+// loadBans reads previously persisted fingerprints from path into mb.banned.
+// A missing file just means nothing has been banned yet.
+func (mb *MessageBroker) loadBans(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create bans directory: %v", err)
 		}
 	}
 
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read ban list: %v", err)
+	}
+
+	var fingerprints []string
+	if err := json.Unmarshal(data, &fingerprints); err != nil {
+		return fmt.Errorf("failed to parse ban list: %v", err)
+	}
+	for _, fp := range fingerprints {
+		mb.banned[fp] = true
+	}
+	return nil
+}
+
+// saveBansLocked rewrites the persisted ban list to disk. Callers must hold
+// mb.mu.
+func (mb *MessageBroker) saveBansLocked() {
+	if mb.bansPath == "" {
+		return
+	}
+
+	fingerprints := make([]string, 0, len(mb.banned))
+	for fp := range mb.banned {
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+
+	data, err := json.MarshalIndent(fingerprints, "", "  ")
+	if err != nil {
+		log.Printf("Failed to marshal ban list: %v", err)
+		return
+	}
+	if err := os.WriteFile(mb.bansPath, data, 0644); err != nil {
+		log.Printf("Failed to persist ban list: %v", err)
+	}
+}
+
+// oldestIDLocked returns the lowest message ID still held in the ring.
+// Callers must hold mb.mu (read or write).
+func (mb *MessageBroker) oldestIDLocked() int {
+	if mb.nextID-1 <= mb.ringCap {
+		return 1
+	}
+	return mb.nextID - mb.ringCap
+}
+
+// AddClient registers a new client with the broker. Its LastSeen cursor is
+// set to just before the oldest message currently in the ring, and the
+// newest message ID that exists at registration time is snapshotted under
+// the same lock; a dedicated goroutine then backfills only up to that
+// snapshot outside of this call, so joining a busy room doesn't block
+// senders. Anything sent after registration is handled by distributeMessages
+// instead, which already sees this client in mb.clients — capping the
+// backfill at the snapshot keeps the two from ever delivering the same
+// message twice.
+func (mb *MessageBroker) AddClient(username, fingerprint string, isAdmin bool) *Client {
+	mb.mu.Lock()
+	now := time.Now()
+	client := &Client{
+		Username:    username,
+		Channel:     make(chan Message, 100),
+		LastSeen:    mb.oldestIDLocked() - 1,
+		Fingerprint: fingerprint,
+		IsAdmin:     isAdmin,
+		KickCh:      make(chan string, 1),
+		JoinTime:    now,
+		LastActive:  now,
+	}
+	mb.clients[username] = client
+	backfillUpTo := mb.nextID - 1
+	mb.mu.Unlock()
+
+	go mb.backfillClient(client, backfillUpTo)
+
 	return client
 }
 
+// backfillClient walks the ring under RLock, replaying history newer than
+// client.LastSeen and no newer than upTo into its channel.
+func (mb *MessageBroker) backfillClient(client *Client, upTo int) {
+	for _, msg := range mb.messagesBetween(client.LastSeen, upTo) {
+		if !mb.sendLocked(client, msg) {
+			return
+		}
+		client.LastSeen = msg.ID
+	}
+}
+
+// sendLocked delivers msg to client's channel, unless the client has since
+// been removed and its channel closed. Callers must not already hold mb.mu.
+// This guards every send that happens outside of distributeMessages (which
+// already holds mb.mu.RLock for the whole scan, so RemoveClient can't close
+// a channel out from under it) against racing a concurrent RemoveClient.
+func (mb *MessageBroker) sendLocked(client *Client, msg Message) bool {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	if client.closed {
+		return false
+	}
+	select {
+	case client.Channel <- msg:
+		return true
+	default:
+		// Client channel is full; don't block forever.
+		return false
+	}
+}
+
+// messagesBetween returns every ring message with cursor < ID <= upTo,
+// oldest first.
+func (mb *MessageBroker) messagesBetween(cursor, upTo int) []Message {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	start := cursor + 1
+	if oldest := mb.oldestIDLocked(); start < oldest {
+		start = oldest
+	}
+
+	var result []Message
+	for id := start; id <= upTo; id++ {
+		result = append(result, mb.ring[(id-1)%mb.ringCap])
+	}
+	return result
+}
+
+// MessagesBefore returns up to limit messages with ID < beforeID, oldest
+// first, for backing the /scroll command. It returns fewer (or none) once
+// the ring's retained history is exhausted.
+func (mb *MessageBroker) MessagesBefore(beforeID, limit int) []Message {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	end := beforeID - 1
+	if end >= mb.nextID {
+		end = mb.nextID - 1
+	}
+	start := end - limit + 1
+	if oldest := mb.oldestIDLocked(); start < oldest {
+		start = oldest
+	}
+	if start > end {
+		return nil
+	}
+
+	result := make([]Message, 0, end-start+1)
+	for id := start; id <= end; id++ {
+		result = append(result, mb.ring[(id-1)%mb.ringCap])
+	}
+	return result
+}
+
 // RemoveClient unregisters a client from the broker
 func (mb *MessageBroker) RemoveClient(username string) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
 	if client, exists := mb.clients[username]; exists {
+		client.closed = true
 		close(client.Channel)
 		delete(mb.clients, username)
 	}
@@ -86,7 +355,14 @@ func (mb *MessageBroker) RemoveClient(username string) {
 // SendMessage adds a new message to the broker
 func (mb *MessageBroker) SendMessage(sender, content string) {
 	mb.mu.Lock()
-	defer mb.mu.Unlock()
+
+	if client, exists := mb.clients[sender]; exists {
+		if client.Muted {
+			mb.mu.Unlock()
+			return
+		}
+		client.LastActive = time.Now()
+	}
 
 	message := Message{
 		ID:        mb.nextID,
@@ -96,7 +372,9 @@ func (mb *MessageBroker) SendMessage(sender, content string) {
 	}
 
 	mb.nextID++
-	mb.messages = append(mb.messages, message)
+	mb.storeInRing(message)
+	mb.persistLocked(message)
+	mb.mu.Unlock()
 
 	// Send to distribution channel
 	select {
@@ -106,6 +384,64 @@ func (mb *MessageBroker) SendMessage(sender, content string) {
 	}
 }
 
+// SendPrivate delivers content only to the sender and recipient's own
+// channels. Private messages aren't added to the scrollback ring or log.
+func (mb *MessageBroker) SendPrivate(from, to, content string) error {
+	mb.mu.Lock()
+	sender, senderExists := mb.clients[from]
+	recipient, recipientExists := mb.clients[to]
+	if !recipientExists {
+		mb.mu.Unlock()
+		return fmt.Errorf("user %q is not online", to)
+	}
+	if senderExists && sender.Muted {
+		mb.mu.Unlock()
+		return fmt.Errorf("you are muted")
+	}
+	if senderExists {
+		sender.LastActive = time.Now()
+	}
+	id := mb.nextID
+	mb.nextID++
+	mb.mu.Unlock()
+
+	message := Message{
+		ID:        id,
+		Sender:    from,
+		Content:   content,
+		Timestamp: time.Now(),
+		Private:   true,
+		Recipient: to,
+	}
+
+	mb.sendLocked(recipient, message)
+	if senderExists && from != to {
+		mb.sendLocked(sender, message)
+	}
+	return nil
+}
+
+// Rename changes a connected client's username, provided the new name isn't
+// already taken.
+func (mb *MessageBroker) Rename(oldName, newName string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if _, taken := mb.clients[newName]; taken {
+		return fmt.Errorf("nickname %q is already taken", newName)
+	}
+
+	client, exists := mb.clients[oldName]
+	if !exists {
+		return fmt.Errorf("user %q is not online", oldName)
+	}
+
+	client.Username = newName
+	delete(mb.clients, oldName)
+	mb.clients[newName] = client
+	return nil
+}
+
 // distributeMessages sends new messages to all connected clients
 func (mb *MessageBroker) distributeMessages() {
 	for message := range mb.nextMessage {
@@ -121,13 +457,125 @@ func (mb *MessageBroker) distributeMessages() {
 	}
 }
 
-// GetAllMessages returns all messages
-func (mb *MessageBroker) GetAllMessages() []Message {
+// ListUsernames returns the usernames of all currently connected clients
+func (mb *MessageBroker) ListUsernames() []string {
 	mb.mu.RLock()
 	defer mb.mu.RUnlock()
 
-	// Return a copy of the messages
-	result := make([]Message, len(mb.messages))
-	copy(result, mb.messages)
+	usernames := make([]string, 0, len(mb.clients))
+	for username := range mb.clients {
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// ListClients returns a metadata snapshot of every connected client.
+func (mb *MessageBroker) ListClients() []Client {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	result := make([]Client, 0, len(mb.clients))
+	for _, c := range mb.clients {
+		result = append(result, Client{
+			Username:    c.Username,
+			Fingerprint: c.Fingerprint,
+			IsAdmin:     c.IsAdmin,
+			Muted:       c.Muted,
+			JoinTime:    c.JoinTime,
+			LastActive:  c.LastActive,
+		})
+	}
 	return result
 }
+
+// WhoIs returns a metadata snapshot of a single connected client.
+func (mb *MessageBroker) WhoIs(username string) (Client, bool) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	c, exists := mb.clients[username]
+	if !exists {
+		return Client{}, false
+	}
+	return Client{
+		Username:    c.Username,
+		Fingerprint: c.Fingerprint,
+		IsAdmin:     c.IsAdmin,
+		Muted:       c.Muted,
+		JoinTime:    c.JoinTime,
+		LastActive:  c.LastActive,
+	}, true
+}
+
+// Kick disconnects a connected client. The client's own TUI tears down the
+// SSH session once it observes the KickCh signal.
+func (mb *MessageBroker) Kick(username string) error {
+	mb.mu.RLock()
+	client, exists := mb.clients[username]
+	mb.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("user %q is not online", username)
+	}
+
+	select {
+	case client.KickCh <- "kicked by an admin":
+	default:
+	}
+	return nil
+}
+
+// Mute silences a connected client; their messages are dropped until unmuted.
+func (mb *MessageBroker) Mute(username string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	client, exists := mb.clients[username]
+	if !exists {
+		return fmt.Errorf("user %q is not online", username)
+	}
+	client.Muted = true
+	return nil
+}
+
+// Unmute lifts a previously applied mute.
+func (mb *MessageBroker) Unmute(username string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	client, exists := mb.clients[username]
+	if !exists {
+		return fmt.Errorf("user %q is not online", username)
+	}
+	client.Muted = false
+	return nil
+}
+
+// Ban permanently blocks a connected client's public key fingerprint from
+// reconnecting and disconnects their current session.
+func (mb *MessageBroker) Ban(username string) error {
+	mb.mu.Lock()
+	client, exists := mb.clients[username]
+	if !exists {
+		mb.mu.Unlock()
+		return fmt.Errorf("user %q is not online", username)
+	}
+	if client.Fingerprint != "" {
+		mb.banned[client.Fingerprint] = true
+		mb.saveBansLocked()
+	}
+	mb.mu.Unlock()
+
+	select {
+	case client.KickCh <- "banned by an admin":
+	default:
+	}
+	return nil
+}
+
+// IsBanned reports whether a public key fingerprint has been banned.
+func (mb *MessageBroker) IsBanned(fingerprint string) bool {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	return mb.banned[fingerprint]
+}
@@ -0,0 +1,41 @@
+package server
+
+import "golang.org/x/crypto/ssh"
+
+// ptyRequestMsg is the RFC 4254 §6.2 payload of a "pty-req" channel request.
+type ptyRequestMsg struct {
+	Term        string
+	Width       uint32
+	Height      uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+	Modes       string
+}
+
+// windowChangeMsg is the RFC 4254 §6.7 payload of a "window-change" channel request.
+type windowChangeMsg struct {
+	Width       uint32
+	Height      uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+}
+
+// parsePtyRequest decodes a pty-req payload, returning the client's terminal
+// size in character cells.
+func parsePtyRequest(payload []byte) (width, height int, ok bool) {
+	var msg ptyRequestMsg
+	if err := ssh.Unmarshal(payload, &msg); err != nil {
+		return 0, 0, false
+	}
+	return int(msg.Width), int(msg.Height), true
+}
+
+// parseWindowChange decodes a window-change payload, returning the new
+// terminal size in character cells.
+func parseWindowChange(payload []byte) (width, height int, ok bool) {
+	var msg windowChangeMsg
+	if err := ssh.Unmarshal(payload, &msg); err != nil {
+		return 0, 0, false
+	}
+	return int(msg.Width), int(msg.Height), true
+}
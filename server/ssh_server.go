@@ -1,7 +1,7 @@
 package server
 
 import (
-	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net"
@@ -13,18 +13,24 @@ import (
 
 // SSHServer represents an SSH server instance
 type SSHServer struct {
-	config   *ssh.ServerConfig
-	listener net.Listener
-	port     string
-	broker   *MessageBroker
+	config    *ssh.ServerConfig
+	listener  net.Listener
+	port      string
+	broker    *MessageBroker
+	admins    map[string]bool // admin public key fingerprints
+	whitelist map[string]bool // nil disables whitelist enforcement
 }
 
-// NewSSHServer creates a new SSH server with the given configuration
-func NewSSHServer(port string, hostKey ssh.Signer) (*SSHServer, error) {
-	config := &ssh.ServerConfig{
-		NoClientAuth: true,
+// NewSSHServer creates a new SSH server with the given configuration.
+// hostKeys are offered to clients in the order given, so clients that
+// negotiate ed25519 (or any other type present) aren't downgraded to
+// whichever key happens to be first. admins is a list of SHA256 public key
+// fingerprints that should be granted admin privileges. whitelist, if
+// non-empty, restricts connections to only the listed fingerprints.
+func NewSSHServer(port string, hostKeys []ssh.Signer, admins []string, whitelist []string) (*SSHServer, error) {
+	if len(hostKeys) == 0 {
+		return nil, fmt.Errorf("at least one host key is required")
 	}
-	config.AddHostKey(hostKey)
 
 	listener, err := net.Listen("tcp", ":"+port)
 	if err != nil {
@@ -34,26 +40,84 @@ func NewSSHServer(port string, hostKey ssh.Signer) (*SSHServer, error) {
 	// Initialize message broker
 	broker := NewMessageBroker()
 
-	// Set up the global chat broker
-	chat.GlobalChatBroker = NewBrokerAdapter(broker)
-
-	return &SSHServer{
-		config:   config,
+	s := &SSHServer{
 		listener: listener,
 		port:     port,
 		broker:   broker,
-	}, nil
+		admins:   toFingerprintSet(admins),
+	}
+	if len(whitelist) > 0 {
+		s.whitelist = toFingerprintSet(whitelist)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: s.publicKeyCallback,
+	}
+	for _, hostKey := range hostKeys {
+		config.AddHostKey(hostKey)
+	}
+	s.config = config
+
+	// Set up the global chat broker
+	chat.GlobalChatBroker = NewBrokerAdapter(broker)
+
+	return s, nil
+}
+
+// toFingerprintSet builds a lookup set from a list of fingerprints.
+func toFingerprintSet(fingerprints []string) map[string]bool {
+	set := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		if fp != "" {
+			set[fp] = true
+		}
+	}
+	return set
 }
 
-// generateIPHash creates a hash of the IP address and returns first 2 and last 2 characters
-func generateIPHash(ipAddr string) string {
-	hash := sha256.Sum256([]byte(ipAddr))
-	hashStr := fmt.Sprintf("%x", hash)
-	// Return first 2 and last 2 characters
-	if len(hashStr) >= 4 {
-		return hashStr[:2] + hashStr[len(hashStr)-2:]
+// publicKeyCallback authenticates a client by its public key, recording the
+// key's SHA256 fingerprint (and whether it belongs to an admin) into the
+// connection's Permissions so a persistent identity survives across
+// sessions, independent of the client's IP address.
+func (s *SSHServer) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	if s.broker.IsBanned(fingerprint) {
+		return nil, fmt.Errorf("public key %s is banned", fingerprint)
 	}
-	return hashStr
+
+	if s.whitelist != nil && !s.whitelist[fingerprint] {
+		return nil, fmt.Errorf("public key %s is not whitelisted", fingerprint)
+	}
+
+	perms := &ssh.Permissions{
+		Extensions: map[string]string{
+			"fingerprint": fingerprint,
+			// Stashed so a later agent-forwarding identity check (see
+			// verifyViaAgent) can reconstruct the ssh.PublicKey to verify
+			// a signature against, without re-deriving it from the
+			// fingerprint alone.
+			"pubkey": base64.StdEncoding.EncodeToString(key.Marshal()),
+		},
+	}
+	if s.admins[fingerprint] {
+		perms.Extensions["admin"] = "true"
+	}
+	return perms, nil
+}
+
+// shortFingerprint trims the "SHA256:" prefix and shortens a fingerprint
+// for display purposes, e.g. as a username suffix.
+func shortFingerprint(fingerprint string) string {
+	const prefix = "SHA256:"
+	trimmed := fingerprint
+	if len(trimmed) > len(prefix) && trimmed[:len(prefix)] == prefix {
+		trimmed = trimmed[len(prefix):]
+	}
+	if len(trimmed) > 8 {
+		return trimmed[:8]
+	}
+	return trimmed
 }
 
 // Start begins listening for SSH connections
@@ -87,22 +151,34 @@ func (s *SSHServer) handleConnection(conn net.Conn) {
 	}
 	defer sshConn.Close()
 
-	// Get the base username and remote IP
+	// Get the base username and the client's verified public key identity
 	baseUsername := sshConn.User()
 	remoteIP := conn.RemoteAddr().String()
-
-	// Extract just the IP address (remove port if present)
 	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
 		remoteIP = host
 	}
 
-	// Generate hash suffix from IP
-	ipHashSuffix := generateIPHash(remoteIP)
+	var fingerprint string
+	var isAdmin bool
+	var pubKey ssh.PublicKey
+	if sshConn.Permissions != nil {
+		fingerprint = sshConn.Permissions.Extensions["fingerprint"]
+		isAdmin = sshConn.Permissions.Extensions["admin"] == "true"
+		if raw, err := base64.StdEncoding.DecodeString(sshConn.Permissions.Extensions["pubkey"]); err == nil {
+			if key, err := ssh.ParsePublicKey(raw); err == nil {
+				pubKey = key
+			}
+		}
+	}
 
-	// Append hash to username
-	username := fmt.Sprintf("%s [%s]", baseUsername, ipHashSuffix)
+	// Append the fingerprint to the username so the same identity persists
+	// across sessions, regardless of the client's IP address. No space
+	// before the bracket: dispatchCommand tokenizes command arguments with
+	// strings.Fields, so a username containing a space could never be
+	// passed whole as a /kick, /msg, or /whois target.
+	username := fmt.Sprintf("%s[%s]", baseUsername, shortFingerprint(fingerprint))
 
-	log.Printf("New SSH connection from %s (%s -> %s)", remoteIP, baseUsername, username)
+	log.Printf("New SSH connection from %s (%s -> %s, admin=%v)", remoteIP, baseUsername, username, isAdmin)
 
 	// Kick users logging in as root or admin (usually bots)
 	if baseUsername == "root" || baseUsername == "admin" {
@@ -127,36 +203,55 @@ func (s *SSHServer) handleConnection(conn net.Conn) {
 			continue
 		}
 
-		go s.handleSession(channel, requests, username)
+		go s.handleSession(sshConn, channel, requests, username, fingerprint, isAdmin, pubKey)
 	}
 }
 
 // handleSession processes SSH session requests
-func (s *SSHServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request, username string) {
+func (s *SSHServer) handleSession(conn ssh.Conn, channel ssh.Channel, requests <-chan *ssh.Request, username, fingerprint string, isAdmin bool, pubKey ssh.PublicKey) {
 	defer channel.Close()
 
 	var tui *chat.ChatTUI
 	tuiStarted := false
+	agentForwarded := false
+	// Sane defaults for clients that open a shell without ever sending a
+	// pty-req (e.g. scripted connections).
+	width, height := 80, 24
 
 	// Handle session requests
 	for req := range requests {
 		switch req.Type {
 		case "pty-req":
+			if w, h, ok := parsePtyRequest(req.Payload); ok {
+				width, height = w, h
+			}
+			req.Reply(true, nil)
+		case "auth-agent-req@openssh.com":
 			req.Reply(true, nil)
+			agentForwarded = true
 		case "shell", "exec":
 			req.Reply(true, nil)
 			if !tuiStarted {
+				if agentForwarded && pubKey != nil {
+					if err := verifyViaAgent(conn, pubKey); err != nil {
+						log.Printf("Agent identity verification failed for %s: %v", username, err)
+						channel.Write([]byte(fmt.Sprintf("Agent identity verification failed: %v\r\n", err)))
+						return
+					}
+					log.Printf("Agent identity verification succeeded for %s", username)
+				}
 				// Start the TUI application with username in a goroutine
-				tui = chat.NewChatTUI(channel, username)
+				tui = chat.NewChatTUI(channel, username, fingerprint, isAdmin, width, height)
 				tuiStarted = true
 				go tui.Run()
 			}
 		case "window-change":
 			req.Reply(true, nil)
-			log.Printf("Window resize event received for user: %s", username)
-			// Handle terminal resize
-			if tui != nil && tuiStarted {
-				tui.HandleResize()
+			if w, h, ok := parseWindowChange(req.Payload); ok {
+				log.Printf("Window resize event received for user: %s (%dx%d)", username, w, h)
+				if tui != nil && tuiStarted {
+					tui.HandleResize(w, h)
+				}
 			}
 		default:
 			req.Reply(false, nil)